@@ -15,5 +15,6 @@ func main() {
 	module.ModularMain(
 		moduleName,
 		resource.APIModel{API: vision.API, Model: hough.Model},
+		resource.APIModel{API: vision.API, Model: hough.LineModel},
 	)
 }
@@ -0,0 +1,93 @@
+package hough
+
+import (
+	"image"
+	"image/color"
+	"sort"
+
+	"go.viam.com/rdk/pointcloud"
+	"go.viam.com/rdk/rimage/transform"
+	vis "go.viam.com/rdk/vision"
+)
+
+// filterCirclesByDepth drops any circle whose median depth (sampled over its pixel
+// disk in depthImg) falls outside [minDepth, maxDepth]. Circles are kept unfiltered
+// when depthImg is nil, e.g. when the camera has no depth source.
+func filterCirclesByDepth(circles []Circle, depthImg image.Image, minDepth, maxDepth uint32) []Circle {
+	if depthImg == nil {
+		return circles
+	}
+
+	filtered := make([]Circle, 0, len(circles))
+	for _, c := range circles {
+		depth, ok := medianDepth(depthImg, c)
+		if !ok || depth < minDepth || depth > maxDepth {
+			continue
+		}
+		filtered = append(filtered, c)
+	}
+	return filtered
+}
+
+// medianDepth samples every pixel of depthImg inside c's disk and returns the median
+// depth in mm. Pixels with a zero reading (no depth data) are skipped.
+func medianDepth(depthImg image.Image, c Circle) (uint32, bool) {
+	bounds := depthImg.Bounds()
+	rSq := c.radius * c.radius
+
+	samples := make([]uint32, 0, 4*rSq)
+	for dy := -c.radius; dy <= c.radius; dy++ {
+		for dx := -c.radius; dx <= c.radius; dx++ {
+			if dx*dx+dy*dy > rSq {
+				continue
+			}
+			p := image.Pt(c.center.X+dx, c.center.Y+dy)
+			if !p.In(bounds) {
+				continue
+			}
+			gray16, ok := color.Gray16Model.Convert(depthImg.At(p.X, p.Y)).(color.Gray16)
+			if !ok || gray16.Y == 0 {
+				continue
+			}
+			samples = append(samples, uint32(gray16.Y))
+		}
+	}
+
+	if len(samples) == 0 {
+		return 0, false
+	}
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	return samples[len(samples)/2], true
+}
+
+// circleToObject back-projects every depth pixel inside c's disk through intrinsics
+// into a point cloud, returning the resulting vision Object.
+func circleToObject(c Circle, depthImg image.Image, intrinsics *transform.PinholeCameraIntrinsics) (*vis.Object, error) {
+	bounds := depthImg.Bounds()
+	rSq := c.radius * c.radius
+
+	cloud := pointcloud.NewBasicEmpty()
+	for dy := -c.radius; dy <= c.radius; dy++ {
+		for dx := -c.radius; dx <= c.radius; dx++ {
+			if dx*dx+dy*dy > rSq {
+				continue
+			}
+			p := image.Pt(c.center.X+dx, c.center.Y+dy)
+			if !p.In(bounds) {
+				continue
+			}
+			gray16, ok := color.Gray16Model.Convert(depthImg.At(p.X, p.Y)).(color.Gray16)
+			if !ok || gray16.Y == 0 {
+				continue
+			}
+
+			x, y, z := intrinsics.PixelToPoint(float64(p.X), float64(p.Y), float64(gray16.Y))
+			if err := cloud.Set(pointcloud.NewVector(x, y, z), pointcloud.NewBasicData()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return vis.NewObject(cloud)
+}
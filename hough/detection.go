@@ -5,13 +5,12 @@ import (
 	"fmt"
 	"image"
 	"image/color"
-	"image/draw"
 	"sort"
 
 	"gocv.io/x/gocv"
 )
 
-// for normalizing
+// default depth bounds (mm) used to reject false-positive circles
 const minDepth uint32 = 300 //mm
 const maxDepth uint32 = 675 //mm
 
@@ -33,8 +32,30 @@ type HoughConfig struct {
 	Param2    float64 `json:"param2,omitempty"`
 	MinRadius int     `json:"min_radius,omitempty"`
 	MaxRadius int     `json:"max_radius,omitempty"`
-	Crop      *image.Rectangle
-	SkipBlur  bool `json:"skip_blur"`
+
+	// HoughMethod selects the circle-detection method: "gradient" (default) for
+	// HOUGH_GRADIENT, or "gradient_alt" for HOUGH_GRADIENT_ALT, whose Param2 is
+	// expected in the 0.8-1.0 range instead of the classic accumulator threshold.
+	HoughMethod string `json:"hough_method,omitempty"`
+
+	// MinDepth and MaxDepth (in mm) bound the median depth a circle's disk must fall
+	// within to be kept; candidates outside this range are assumed to be false
+	// positives rather than the vessel of interest. Filtering uses the "depth" image
+	// source of the configured camera; without one, and in Detections (which is given
+	// only a color image), circles are not depth-filtered.
+	MinDepth uint32 `json:"min_depth,omitempty"`
+	MaxDepth uint32 `json:"max_depth,omitempty"`
+
+	// MaxCenterDriftPx and MaxRadiusChangePx gate the tracker's frame-to-frame
+	// circle matching: a raw detection can only extend an existing track if it
+	// falls within this center distance and radius change of the track's Kalman
+	// prediction. MaxMissedFrames is how many consecutive frames a track may go
+	// unmatched, surviving on its prediction, before it's evicted.
+	MaxCenterDriftPx  float64 `json:"max_center_drift_px,omitempty"`
+	MaxRadiusChangePx float64 `json:"max_radius_change_px,omitempty"`
+	MaxMissedFrames   int     `json:"max_missed_frames,omitempty"`
+
+	PreprocessConfig
 }
 
 // Validate validates the config and returns implicit dependencies,
@@ -67,6 +88,36 @@ func (cfg *HoughConfig) Validate(path string) ([]string, error) {
 		return nil, fmt.Errorf("max_radius needs to be set (def 50)")
 	}
 
+	switch cfg.HoughMethod {
+	case "", "gradient", "gradient_alt":
+	default:
+		return nil, fmt.Errorf(`hough_method must be "gradient" or "gradient_alt", got %q`, cfg.HoughMethod)
+	}
+
+	if cfg.MinDepth <= 0 {
+		return nil, fmt.Errorf("min_depth needs to be set (def 300)")
+	}
+
+	if cfg.MaxDepth <= 0 {
+		return nil, fmt.Errorf("max_depth needs to be set (def 675)")
+	}
+
+	if cfg.MaxCenterDriftPx <= 0 {
+		return nil, fmt.Errorf("max_center_drift_px needs to be set (def 40)")
+	}
+
+	if cfg.MaxRadiusChangePx <= 0 {
+		return nil, fmt.Errorf("max_radius_change_px needs to be set (def 15)")
+	}
+
+	if cfg.MaxMissedFrames <= 0 {
+		return nil, fmt.Errorf("max_missed_frames needs to be set (def 5)")
+	}
+
+	if err := cfg.PreprocessConfig.validate(); err != nil {
+		return nil, err
+	}
+
 	return []string{cfg.CameraName}, nil
 }
 
@@ -77,25 +128,29 @@ func (hc *HoughConfig) setDefaults() {
 	hc.Param2 = 25
 	hc.MinRadius = 35
 	hc.MaxRadius = 50
+	hc.HoughMethod = "gradient"
+	hc.MinDepth = minDepth
+	hc.MaxDepth = maxDepth
+	hc.MaxCenterDriftPx = 40
+	hc.MaxRadiusChangePx = 15
+	hc.MaxMissedFrames = 5
+	hc.PreprocessConfig.setDefaults()
+}
+
+func houghMethodFor(name string) gocv.HoughMode {
+	if name == "gradient_alt" {
+		return gocv.HoughGradientAlt
+	}
+	return gocv.HoughGradient
 }
 
 func vesselCircles(img image.Image, hc *HoughConfig, outputBlur bool, outputResults string) ([]Circle, error) {
-	croppedImg := cropImage(img, hc.Crop)
-	mat := imageToMat(croppedImg)
+	mat, gray, err := preprocessImage(img, &hc.PreprocessConfig, outputBlur)
+	if err != nil {
+		return nil, err
+	}
 	defer mat.Close()
-
-	gray := gocv.NewMat()
 	defer gray.Close()
-	gocv.CvtColor(mat, &gray, gocv.ColorBGRToGray)
-
-	if !hc.SkipBlur { // Blur to reduce noise
-		gocv.MedianBlur(gray, &gray, 15)
-		if outputBlur {
-			if ok := gocv.IMWrite("blurred.jpg", gray); !ok {
-				return nil, errors.New("failed to save the output image")
-			}
-		}
-	}
 
 	circles := gocv.NewMat()
 	defer circles.Close()
@@ -103,15 +158,15 @@ func vesselCircles(img image.Image, hc *HoughConfig, outputBlur bool, outputResu
 	// READ MORE ABOUT THIS HERE:
 	// https://docs.opencv.org/4.x/dd/d1a/group__imgproc__feature.html#ga47849c3be0d0406ad3ca45db65a25d2d
 	gocv.HoughCirclesWithParams(
-		gray,               // src
-		&circles,           // circles
-		gocv.HoughGradient, // method - only HoughGradient is supported
-		hc.Dp,              // dp: inverse ratio of the accumulator resolution to the image resolution
-		hc.MinDist,         // minDist: minimum distance between the centers of detected circles (Question: how is distance calculated here?)
-		hc.Param1,          // param1: the higher threshold for the canny edge detector
-		hc.Param2,          // param2: the accumulator threshold for circle detection
-		hc.MinRadius,       // minRadius of bounding circle
-		hc.MaxRadius,       // maxRadius of bouding circle
+		gray,                           // src
+		&circles,                       // circles
+		houghMethodFor(hc.HoughMethod), // method: HOUGH_GRADIENT or HOUGH_GRADIENT_ALT
+		hc.Dp,                          // dp: inverse ratio of the accumulator resolution to the image resolution
+		hc.MinDist,                     // minDist: minimum distance between the centers of detected circles (Question: how is distance calculated here?)
+		hc.Param1,                      // param1: the higher threshold for the canny edge detector
+		hc.Param2,                      // param2: the accumulator threshold for circle detection (0.8-1.0 for gradient_alt)
+		hc.MinRadius,                   // minRadius of bounding circle
+		hc.MaxRadius,                   // maxRadius of bouding circle
 	)
 
 	goodCircles := make([]Circle, 0)
@@ -146,34 +201,3 @@ func vesselCircles(img image.Image, hc *HoughConfig, outputBlur bool, outputResu
 	})
 	return goodCircles, nil
 }
-
-func cropImage(src image.Image, crop *image.Rectangle) image.Image {
-	if crop == nil {
-		return src
-	}
-	// Create a new RGBA image with the size of the crop rectangle
-	croppedImg := image.NewRGBA(image.Rect(0, 0, crop.Dx(), crop.Dy()))
-
-	// Adjust the draw point to correctly position the cropped area
-	draw.Draw(croppedImg, croppedImg.Bounds(), src, crop.Min, draw.Src)
-	return croppedImg
-}
-
-func imageToMat(img image.Image) gocv.Mat {
-	bounds := img.Bounds()
-	width, height := bounds.Dx(), bounds.Dy()
-
-	mat := gocv.NewMatWithSize(height, width, gocv.MatTypeCV8UC3)
-
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			r, g, b, _ := img.At(x+bounds.Min.X, y+bounds.Min.Y).RGBA()
-			// Convert from 0-65535 to 0-255
-			mat.SetUCharAt(y, x*3, uint8(b>>8))
-			mat.SetUCharAt(y, x*3+1, uint8(g>>8))
-			mat.SetUCharAt(y, x*3+2, uint8(r>>8))
-		}
-	}
-
-	return mat
-}
@@ -12,8 +12,10 @@ func TestHough1(t *testing.T) {
 	test.That(t, err, test.ShouldBeNil)
 
 	c := &HoughConfig{
-		Crop:     &image.Rectangle{Min: image.Pt(115, 0), Max: image.Pt(600, 440)},
-		SkipBlur: true,
+		PreprocessConfig: PreprocessConfig{
+			Crop:     &image.Rectangle{Min: image.Pt(115, 0), Max: image.Pt(600, 440)},
+			SkipBlur: true,
+		},
 	}
 	c.setDefaults()
 	c.MinDist = float64(c.MinRadius)
@@ -22,3 +24,21 @@ func TestHough1(t *testing.T) {
 	test.That(t, err, test.ShouldBeNil)
 	test.That(t, len(circles), test.ShouldEqual, 3)
 }
+
+func TestHoughGaussianBlur(t *testing.T) {
+	img, err := openImage("data/a1.jpg")
+	test.That(t, err, test.ShouldBeNil)
+
+	c := &HoughConfig{
+		PreprocessConfig: PreprocessConfig{
+			Crop: &image.Rectangle{Min: image.Pt(115, 0), Max: image.Pt(600, 440)},
+		},
+	}
+	c.setDefaults()
+	c.BlurType = "gaussian"
+	c.MinDist = float64(c.MinRadius)
+
+	circles, err := vesselCircles(img, c, false, "a1-gaussian-output.jpg")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, len(circles), test.ShouldBeGreaterThan, 0)
+}
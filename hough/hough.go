@@ -2,11 +2,14 @@
 package hough
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"fmt"
 	"math/rand"
 	"os"
 	"strconv"
+	"sync"
 
 	"image"
 
@@ -43,7 +46,11 @@ type myHoughTransformer struct {
 
 	logger logging.Logger
 	cam    camera.Camera
+
+	confMu sync.RWMutex
 	conf   *HoughConfig
+
+	trk *tracker
 }
 
 func newHoughTransformer(ctx context.Context, deps resource.Dependencies, conf resource.Config, logger logging.Logger) (vision.Service, error) {
@@ -56,6 +63,7 @@ func newHoughTransformer(ctx context.Context, deps resource.Dependencies, conf r
 	h := &myHoughTransformer{
 		logger: logger,
 		conf:   newConf,
+		trk:    newTracker(),
 	}
 
 	h.cam, err = camera.FromDependencies(deps, newConf.CameraName)
@@ -71,26 +79,32 @@ func (h *myHoughTransformer) DetectionsFromCamera(
 	cameraName string,
 	extra map[string]interface{},
 ) ([]objdet.Detection, error) {
-	colorImg, err := h.getImage(ctx)
+	colorImg, depthImg, err := h.getImages(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	detections, err := h.Detections(ctx, colorImg, map[string]interface{}{"addOffset": true})
+	h.confMu.RLock()
+	conf := *h.conf
+	h.confMu.RUnlock()
+
+	circles, err := vesselCircles(colorImg, &conf, false, "")
 	if err != nil {
 		return nil, err
 	}
+	circles = filterCirclesByDepth(circles, depthImg, conf.MinDepth, conf.MaxDepth)
 
-	return detections, nil
+	tracked := h.trk.update(circles, conf.MaxCenterDriftPx, conf.MaxRadiusChangePx, conf.MaxMissedFrames)
+
+	return formatTrackedDetections(tracked), nil
 }
 
 func (h *myHoughTransformer) Detections(ctx context.Context, img image.Image, extra map[string]interface{}) ([]objdet.Detection, error) {
-	addOffset, ok := extra["addOffset"].(bool)
-	if !ok {
-		return nil, errors.New("we do not know if we should add an offset to the detections, please specify")
-	}
+	h.confMu.RLock()
+	conf := *h.conf
+	h.confMu.RUnlock()
 
-	circles, err := vesselCircles(img, h.conf, addOffset, false, "")
+	circles, err := vesselCircles(img, &conf, false, "")
 	if err != nil {
 		return nil, err
 	}
@@ -117,15 +131,51 @@ func (h *myHoughTransformer) GetProperties(ctx context.Context, extra map[string
 	return &vision.Properties{
 		DetectionSupported:      true,
 		ClassificationSupported: false,
-		ObjectPCDsSupported:     false,
+		ObjectPCDsSupported:     true,
 	}, nil
 }
+
 func (h *myHoughTransformer) GetObjectPointClouds(
 	ctx context.Context,
 	cameraName string,
 	extra map[string]interface{},
 ) ([]*vis.Object, error) {
-	return nil, errUnimplemented
+	colorImg, depthImg, err := h.getImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if depthImg == nil {
+		return nil, errors.New("camera has no \"depth\" image source, cannot back-project circles into point clouds")
+	}
+
+	props, err := h.cam.Properties(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if props.IntrinsicParams == nil {
+		return nil, errors.New("camera has no intrinsic parameters, cannot back-project circles into point clouds")
+	}
+
+	h.confMu.RLock()
+	conf := *h.conf
+	h.confMu.RUnlock()
+
+	circles, err := vesselCircles(colorImg, &conf, false, "")
+	if err != nil {
+		return nil, err
+	}
+	circles = filterCirclesByDepth(circles, depthImg, conf.MinDepth, conf.MaxDepth)
+
+	objects := make([]*vis.Object, 0, len(circles))
+	for _, c := range circles {
+		obj, err := circleToObject(c, depthImg, props.IntrinsicParams)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, obj)
+	}
+
+	return objects, nil
 }
 
 func (h *myHoughTransformer) CaptureAllFromCamera(
@@ -135,19 +185,25 @@ func (h *myHoughTransformer) CaptureAllFromCamera(
 	extra map[string]interface{},
 ) (viscapture.VisCapture, error) {
 
-	colorImg, err := h.getImage(ctx)
+	colorImg, depthImg, err := h.getImages(ctx)
 	if err != nil {
 		return viscapture.VisCapture{}, err
 	}
 
 	output := fmt.Sprintf("output-%d.jpg", rand.Int()%1000)
 
-	circles, err := vesselCircles(colorImg, h.conf, false, false, output)
+	h.confMu.RLock()
+	conf := *h.conf
+	h.confMu.RUnlock()
+
+	circles, err := vesselCircles(colorImg, &conf, false, output)
 	if err != nil {
 		return viscapture.VisCapture{}, err
 	}
+	circles = filterCirclesByDepth(circles, depthImg, conf.MinDepth, conf.MaxDepth)
 
-	detections := formatDetections(circles)
+	tracked := h.trk.update(circles, conf.MaxCenterDriftPx, conf.MaxRadiusChangePx, conf.MaxMissedFrames)
+	detections := formatTrackedDetections(tracked)
 
 	croppedColorImg, err := openImage(output)
 	if err != nil {
@@ -166,23 +222,270 @@ func (h *myHoughTransformer) Close(ctx context.Context) error {
 	return nil
 }
 
+// DoCommand supports four shapes of request:
+//   - {"probe": true, "image": "<base64 jpeg/png>"}: runs a single detection pass
+//     against the supplied image using the current config and returns the detected
+//     circles plus base64-encoded intermediate images (blurred/edges), for tuning UIs.
+//   - {"reset_tracks": ...}: drops every track and restarts track ids at 0.
+//   - {"dump_tracks": ...}: returns the id, center and radius of every live track.
+//   - any other map patches the live config in place (dp, min_dist, param1, param2,
+//     min_radius, max_radius, crop, skip_blur, and the preprocessing fields added in
+//     PreprocessConfig) and returns the resulting effective config.
 func (h *myHoughTransformer) DoCommand(ctx context.Context, cmd map[string]interface{}) (map[string]interface{}, error) {
-	return nil, errors.New("called DoCommand but nothing was executed")
+	if probe, ok := cmd["probe"].(bool); ok && probe {
+		return h.probe(cmd)
+	}
+
+	if _, ok := cmd["reset_tracks"]; ok {
+		h.trk.reset()
+		return map[string]interface{}{"reset": true}, nil
+	}
+
+	if _, ok := cmd["dump_tracks"]; ok {
+		return map[string]interface{}{"tracks": formatTrackDump(h.trk.dump())}, nil
+	}
+
+	h.confMu.Lock()
+	defer h.confMu.Unlock()
+
+	if err := patchHoughConfig(h.conf, cmd); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{"config": h.conf}, nil
 }
 
-func (h *myHoughTransformer) getImage(ctx context.Context) (image.Image, error) {
-	images, _, err := h.cam.Images(ctx)
+func formatTrackDump(tracked []trackedCircle) []map[string]interface{} {
+	tracks := make([]map[string]interface{}, len(tracked))
+	for i, t := range tracked {
+		tracks[i] = map[string]interface{}{
+			"id":     t.id,
+			"center": []int{t.circle.center.X, t.circle.center.Y},
+			"radius": t.circle.radius,
+		}
+	}
+	return tracks
+}
+
+func (h *myHoughTransformer) probe(cmd map[string]interface{}) (map[string]interface{}, error) {
+	encoded, ok := cmd["image"].(string)
+	if !ok {
+		return nil, errors.New(`probe requires an "image" field containing a base64-encoded image`)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode probe image")
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode probe image")
+	}
+
+	h.confMu.RLock()
+	conf := *h.conf
+	h.confMu.RUnlock()
+
+	const probeOutput = "probe-output.jpg"
+	circles, err := vesselCircles(img, &conf, true, probeOutput)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(probeOutput)
+	defer os.Remove("blurred.jpg")
+	defer os.Remove("edges.jpg")
+
+	results := make([]map[string]interface{}, len(circles))
+	for i, c := range circles {
+		results[i] = map[string]interface{}{
+			"center": []int{c.center.X, c.center.Y},
+			"radius": c.radius,
+		}
+	}
+
+	out := map[string]interface{}{"circles": results}
+	if b, err := encodeImageFile("blurred.jpg"); err == nil {
+		out["blurred"] = b
+	}
+	if b, err := encodeImageFile("edges.jpg"); err == nil {
+		out["edges"] = b
+	}
+
+	return out, nil
+}
+
+func encodeImageFile(fn string) (string, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// patchHoughConfig applies any recognized keys in cmd to a copy of hc, validates
+// the resulting config as a whole, and only then swaps it into hc. This keeps a
+// bad patch (a negative param, an even blur_kernel, an unknown hough_method, ...)
+// from being partially applied or from bypassing the checks HoughConfig.Validate
+// already enforces at startup.
+func patchHoughConfig(hc *HoughConfig, cmd map[string]interface{}) error {
+	proposed := *hc
+	if err := applyHoughConfigPatch(&proposed, cmd); err != nil {
+		return err
+	}
+
+	if _, err := proposed.Validate("DoCommand"); err != nil {
+		return err
+	}
+
+	*hc = proposed
+	return nil
+}
+
+// applyHoughConfigPatch applies any recognized keys in cmd to hc in place.
+func applyHoughConfigPatch(hc *HoughConfig, cmd map[string]interface{}) error {
+	floatFields := map[string]*float64{
+		"dp":                   &hc.Dp,
+		"min_dist":             &hc.MinDist,
+		"param1":               &hc.Param1,
+		"param2":               &hc.Param2,
+		"gaussian_sigma":       &hc.GaussianSigma,
+		"canny_low":            &hc.CannyLow,
+		"canny_high":           &hc.CannyHigh,
+		"max_center_drift_px":  &hc.MaxCenterDriftPx,
+		"max_radius_change_px": &hc.MaxRadiusChangePx,
+	}
+	for key, field := range floatFields {
+		if v, ok := cmd[key]; ok {
+			f, err := toFloat64(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			*field = f
+		}
+	}
+
+	intFields := map[string]*int{
+		"min_radius":        &hc.MinRadius,
+		"max_radius":        &hc.MaxRadius,
+		"blur_kernel":       &hc.BlurKernel,
+		"max_missed_frames": &hc.MaxMissedFrames,
+	}
+	for key, field := range intFields {
+		if v, ok := cmd[key]; ok {
+			f, err := toFloat64(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			*field = int(f)
+		}
+	}
+
+	if v, ok := cmd["min_depth"]; ok {
+		f, err := toFloat64(v)
+		if err != nil {
+			return fmt.Errorf("min_depth: %w", err)
+		}
+		hc.MinDepth = uint32(f)
+	}
+
+	if v, ok := cmd["max_depth"]; ok {
+		f, err := toFloat64(v)
+		if err != nil {
+			return fmt.Errorf("max_depth: %w", err)
+		}
+		hc.MaxDepth = uint32(f)
+	}
+
+	if v, ok := cmd["skip_blur"]; ok {
+		b, ok := v.(bool)
+		if !ok {
+			return errors.New("skip_blur must be a bool")
+		}
+		hc.SkipBlur = b
+	}
+
+	if v, ok := cmd["blur_type"]; ok {
+		s, ok := v.(string)
+		if !ok {
+			return errors.New("blur_type must be a string")
+		}
+		hc.BlurType = s
+	}
+
+	if v, ok := cmd["hough_method"]; ok {
+		s, ok := v.(string)
+		if !ok {
+			return errors.New("hough_method must be a string")
+		}
+		hc.HoughMethod = s
+	}
+
+	if v, ok := cmd["crop"]; ok {
+		rect, err := toRect(v)
+		if err != nil {
+			return errors.Wrap(err, "crop")
+		}
+		hc.Crop = rect
+	}
+
+	return nil
+}
+
+func toFloat64(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case int:
+		return float64(n), nil
+	default:
+		return 0, fmt.Errorf("expected a number, got %T", v)
+	}
+}
+
+func toRect(v interface{}) (*image.Rectangle, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("must be an object with min_x, min_y, max_x, max_y")
+	}
+
+	minX, err := toFloat64(m["min_x"])
+	if err != nil {
+		return nil, err
+	}
+	minY, err := toFloat64(m["min_y"])
 	if err != nil {
 		return nil, err
 	}
+	maxX, err := toFloat64(m["max_x"])
+	if err != nil {
+		return nil, err
+	}
+	maxY, err := toFloat64(m["max_y"])
+	if err != nil {
+		return nil, err
+	}
+
+	rect := image.Rect(int(minX), int(minY), int(maxX), int(maxY))
+	return &rect, nil
+}
 
-	var colorImg image.Image
+func (h *myHoughTransformer) getImages(ctx context.Context) (image.Image, image.Image, error) {
+	images, _, err := h.cam.Images(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var colorImg, depthImg image.Image
 	for _, img := range images {
-		if img.SourceName == "color" {
+		switch img.SourceName {
+		case "color":
 			colorImg = img.Image
+		case "depth":
+			depthImg = img.Image
 		}
 	}
-	return colorImg, nil
+	return colorImg, depthImg, nil
 }
 
 func formatDetections(circles []Circle) []objdet.Detection {
@@ -202,8 +505,28 @@ func formatDetections(circles []Circle) []objdet.Detection {
 	return detections
 }
 
+// formatTrackedDetections is like formatDetections, but names each detection after
+// its persistent track id (e.g. "circle-id-7") instead of its index in this frame.
+func formatTrackedDetections(tracked []trackedCircle) []objdet.Detection {
+	var detections []objdet.Detection
+	for _, t := range tracked {
+		c := t.circle
+		minX := c.center.X - (c.radius)
+		maxX := c.center.X + (c.radius)
+		minY := c.center.Y - (c.radius)
+		maxY := c.center.Y + (c.radius)
+		rect := image.Rectangle{
+			Min: image.Point{X: minX, Y: minY},
+			Max: image.Point{X: maxX, Y: maxY},
+		}
+		name := fmt.Sprintf("circle-id-%d", t.id)
+		detections = append(detections, objdet.NewDetection(rect, 1, name))
+	}
+	return detections
+}
+
 func openImage(fn string) (image.Image, error) {
-	file, err := os.Open("output.jpg")
+	file, err := os.Open(fn)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,135 @@
+package hough
+
+import (
+	"image"
+	"image/color"
+
+	"gocv.io/x/gocv"
+)
+
+// imageToMat converts img into a BGR CV8UC3 Mat. It tries imageToMatFastPath first,
+// which bulk-copies the common concrete image.Image implementations' Pix slice
+// straight into a BGR buffer and hands it to OpenCV in one cgo call. Anything that
+// doesn't match one of those concrete types falls back to imageToMatSlow, which
+// walks every pixel through the generic image.Image interface.
+func imageToMat(img image.Image) gocv.Mat {
+	if mat, ok := imageToMatFastPath(img); ok {
+		return mat
+	}
+	return imageToMatSlow(img)
+}
+
+// imageToMatFastPath handles *image.RGBA, *image.NRGBA, *image.YCbCr, and
+// *image.Gray -- the formats gocv.IMWrite/image.Decode and most camera drivers
+// actually hand back -- without ever going through img.At(...).RGBA().
+func imageToMatFastPath(img image.Image) (gocv.Mat, bool) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	buf, ok := bgrBytesFromImage(img, bounds, width, height)
+	if !ok {
+		return gocv.Mat{}, false
+	}
+
+	mat, err := gocv.NewMatFromBytes(height, width, gocv.MatTypeCV8UC3, buf)
+	if err != nil {
+		return gocv.Mat{}, false
+	}
+	return mat, true
+}
+
+func bgrBytesFromImage(img image.Image, bounds image.Rectangle, width, height int) ([]byte, bool) {
+	buf := make([]byte, width*height*3)
+
+	switch src := img.(type) {
+	case *image.RGBA:
+		for y := 0; y < height; y++ {
+			srcRow := src.Pix[(y+bounds.Min.Y-src.Rect.Min.Y)*src.Stride:]
+			dstRow := buf[y*width*3:]
+			for x := 0; x < width; x++ {
+				si := (x + bounds.Min.X - src.Rect.Min.X) * 4
+				dstRow[x*3] = srcRow[si+2]   // B
+				dstRow[x*3+1] = srcRow[si+1] // G
+				dstRow[x*3+2] = srcRow[si]   // R
+			}
+		}
+
+	case *image.NRGBA:
+		for y := 0; y < height; y++ {
+			srcRow := src.Pix[(y+bounds.Min.Y-src.Rect.Min.Y)*src.Stride:]
+			dstRow := buf[y*width*3:]
+			for x := 0; x < width; x++ {
+				si := (x + bounds.Min.X - src.Rect.Min.X) * 4
+				a := srcRow[si+3]
+				// NRGBA stores non-premultiplied color, but image.Image.At(...).RGBA()
+				// (what imageToMatSlow uses) always returns alpha-premultiplied values.
+				// Premultiply here too so the two paths agree on non-opaque pixels.
+				dstRow[x*3] = premultiplyNRGBA(srcRow[si+2], a)   // B
+				dstRow[x*3+1] = premultiplyNRGBA(srcRow[si+1], a) // G
+				dstRow[x*3+2] = premultiplyNRGBA(srcRow[si], a)   // R
+			}
+		}
+
+	case *image.Gray:
+		for y := 0; y < height; y++ {
+			srcRow := src.Pix[(y+bounds.Min.Y-src.Rect.Min.Y)*src.Stride:]
+			dstRow := buf[y*width*3:]
+			for x := 0; x < width; x++ {
+				v := srcRow[x+bounds.Min.X-src.Rect.Min.X]
+				dstRow[x*3] = v
+				dstRow[x*3+1] = v
+				dstRow[x*3+2] = v
+			}
+		}
+
+	case *image.YCbCr:
+		for y := 0; y < height; y++ {
+			dstRow := buf[y*width*3:]
+			for x := 0; x < width; x++ {
+				yi := src.YOffset(bounds.Min.X+x, bounds.Min.Y+y)
+				ci := src.COffset(bounds.Min.X+x, bounds.Min.Y+y)
+				r, g, b := color.YCbCrToRGB(src.Y[yi], src.Cb[ci], src.Cr[ci])
+				dstRow[x*3] = b
+				dstRow[x*3+1] = g
+				dstRow[x*3+2] = r
+			}
+		}
+
+	default:
+		return nil, false
+	}
+
+	return buf, true
+}
+
+// premultiplyNRGBA alpha-premultiplies an 8-bit NRGBA channel value the same way
+// color.NRGBA.RGBA does (scale to 16-bit, multiply by alpha, divide by 0xff, scale
+// back down), so the fast path matches img.At(...).RGBA() bit-for-bit.
+func premultiplyNRGBA(v, a byte) byte {
+	vv := uint32(v)
+	vv |= vv << 8
+	vv *= uint32(a)
+	vv /= 0xff
+	return uint8(vv >> 8)
+}
+
+// imageToMatSlow is the original reflective path, kept as a fallback for any
+// image.Image implementation imageToMatFastPath doesn't recognize.
+func imageToMatSlow(img image.Image) gocv.Mat {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	mat := gocv.NewMatWithSize(height, width, gocv.MatTypeCV8UC3)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(x+bounds.Min.X, y+bounds.Min.Y).RGBA()
+			// Convert from 0-65535 to 0-255
+			mat.SetUCharAt(y, x*3, uint8(b>>8))
+			mat.SetUCharAt(y, x*3+1, uint8(g>>8))
+			mat.SetUCharAt(y, x*3+2, uint8(r>>8))
+		}
+	}
+
+	return mat
+}
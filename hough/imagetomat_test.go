@@ -0,0 +1,105 @@
+package hough
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"go.viam.com/test"
+	"gocv.io/x/gocv"
+)
+
+// buildTestImages returns the same pixel content encoded as each concrete type
+// imageToMatFastPath special-cases, plus a paletted image it does not, so the
+// fallback path gets exercised too. The NRGBA image also gets a non-opaque case
+// (nrgbaTranslucent) since alpha-premultiplication only kicks in for A<255.
+func buildTestImages(bounds image.Rectangle) map[string]image.Image {
+	rgba := image.NewRGBA(bounds)
+	nrgba := image.NewNRGBA(bounds)
+	nrgbaTranslucent := image.NewNRGBA(bounds)
+	gray := image.NewGray(bounds)
+	ycbcr := image.NewYCbCr(bounds, image.YCbCrSubsampleRatio444)
+	paletted := image.NewPaletted(bounds, color.Palette{color.Black, color.White})
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.RGBA{
+				R: uint8((x * 7) % 256),
+				G: uint8((y * 13) % 256),
+				B: uint8((x + y) % 256),
+				A: 255,
+			}
+			rgba.Set(x, y, c)
+			nrgba.Set(x, y, c)
+			gray.Set(x, y, c)
+			paletted.Set(x, y, c)
+			nrgbaTranslucent.Set(x, y, color.NRGBA{
+				R: c.R,
+				G: c.G,
+				B: c.B,
+				A: uint8((x*31 + y*17) % 256),
+			})
+
+			yy, cb, cr := color.RGBToYCbCr(c.R, c.G, c.B)
+			ycbcr.Y[ycbcr.YOffset(x, y)] = yy
+			ycbcr.Cb[ycbcr.COffset(x, y)] = cb
+			ycbcr.Cr[ycbcr.COffset(x, y)] = cr
+		}
+	}
+
+	return map[string]image.Image{
+		"RGBA":             rgba,
+		"NRGBA":            nrgba,
+		"NRGBATranslucent": nrgbaTranslucent,
+		"Gray":             gray,
+		"YCbCr":            ycbcr,
+		"Paletted":         paletted,
+	}
+}
+
+func TestImageToMatFastPathParity(t *testing.T) {
+	// Odd, non-square dimensions to catch stride/offset bugs.
+	bounds := image.Rect(0, 0, 37, 23)
+
+	for name, img := range buildTestImages(bounds) {
+		t.Run(name, func(t *testing.T) {
+			fast := imageToMat(img)
+			defer fast.Close()
+			slow := imageToMatSlow(img)
+			defer slow.Close()
+
+			test.That(t, fast.Rows(), test.ShouldEqual, slow.Rows())
+			test.That(t, fast.Cols(), test.ShouldEqual, slow.Cols())
+
+			for y := 0; y < slow.Rows(); y++ {
+				for x := 0; x < slow.Cols(); x++ {
+					for c := 0; c < 3; c++ {
+						test.That(t, fast.GetUCharAt(y, x*3+c), test.ShouldEqual, slow.GetUCharAt(y, x*3+c))
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkImageToMat compares the fast and slow paths on a realistic frame size
+// to quantify the win the bulk byte-copy path is meant to provide.
+func BenchmarkImageToMat(b *testing.B) {
+	bounds := image.Rect(0, 0, 1280, 720)
+	images := buildTestImages(bounds)
+
+	b.Run("Fast/RGBA", func(b *testing.B) { benchmarkImageToMat(b, images["RGBA"], imageToMat) })
+	b.Run("Slow/RGBA", func(b *testing.B) { benchmarkImageToMat(b, images["RGBA"], imageToMatSlow) })
+	b.Run("Fast/NRGBA", func(b *testing.B) { benchmarkImageToMat(b, images["NRGBA"], imageToMat) })
+	b.Run("Slow/NRGBA", func(b *testing.B) { benchmarkImageToMat(b, images["NRGBA"], imageToMatSlow) })
+	b.Run("Fast/Paletted", func(b *testing.B) { benchmarkImageToMat(b, images["Paletted"], imageToMat) })
+	b.Run("Slow/Paletted", func(b *testing.B) { benchmarkImageToMat(b, images["Paletted"], imageToMatSlow) })
+}
+
+func benchmarkImageToMat(b *testing.B, img image.Image, convert func(image.Image) gocv.Mat) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		mat := convert(img)
+		mat.Close()
+	}
+}
@@ -0,0 +1,148 @@
+package hough
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"gocv.io/x/gocv"
+)
+
+// Line is a detected line segment, described by its two endpoints.
+type Line struct {
+	p1, p2 image.Point
+}
+
+// LineConfig contains names for necessary resources (camera) and the
+// HoughLinesP tuning parameters.
+type LineConfig struct {
+	CameraName string `json:"camera_name"`
+
+	Rho           float64 `json:"rho,omitempty"`
+	Theta         float64 `json:"theta,omitempty"`
+	Threshold     int     `json:"threshold,omitempty"`
+	MinLineLength float64 `json:"min_line_length,omitempty"`
+	MaxLineGap    float64 `json:"max_line_gap,omitempty"`
+
+	PreprocessConfig
+}
+
+// Validate validates the config and returns implicit dependencies.
+func (cfg *LineConfig) Validate(path string) ([]string, error) {
+	if cfg.CameraName == "" {
+		return nil, fmt.Errorf(`expected "camera_name" attribute for line detector %q`, path)
+	}
+
+	if cfg.Rho <= 0 {
+		return nil, fmt.Errorf("rho needs to be set (def 1)")
+	}
+
+	if cfg.Theta <= 0 {
+		return nil, fmt.Errorf("theta needs to be set (def pi/180)")
+	}
+
+	if cfg.Threshold <= 0 {
+		return nil, fmt.Errorf("threshold needs to be set (def 50)")
+	}
+
+	if cfg.MinLineLength <= 0 {
+		return nil, fmt.Errorf("min_line_length needs to be set (def 50)")
+	}
+
+	if cfg.MaxLineGap <= 0 {
+		return nil, fmt.Errorf("max_line_gap needs to be set (def 10)")
+	}
+
+	if err := cfg.PreprocessConfig.validate(); err != nil {
+		return nil, err
+	}
+
+	return []string{cfg.CameraName}, nil
+}
+
+func (cfg *LineConfig) setDefaults() {
+	cfg.Rho = 1
+	cfg.Theta = math.Pi / 180
+	cfg.Threshold = 50
+	cfg.MinLineLength = 50
+	cfg.MaxLineGap = 10
+	cfg.PreprocessConfig.setDefaults()
+}
+
+// vesselLines runs the shared preprocessing stage and HoughLinesP, returning the
+// detected line segments ordered longest-first.
+func vesselLines(img image.Image, lc *LineConfig, outputBlur bool, outputResults string) ([]Line, error) {
+	mat, gray, err := preprocessImage(img, &lc.PreprocessConfig, outputBlur)
+	if err != nil {
+		return nil, err
+	}
+	defer mat.Close()
+	defer gray.Close()
+
+	segments := gocv.NewMat()
+	defer segments.Close()
+
+	// READ MORE ABOUT THIS HERE:
+	// https://docs.opencv.org/4.x/dd/d1a/group__imgproc__feature.html#ga8618180a5948286384e3b7ca02f6feeb
+	gocv.HoughLinesPWithParams(
+		gray,             // src
+		&segments,        // lines
+		lc.Rho,           // rho: distance resolution of the accumulator in pixels
+		lc.Theta,         // theta: angle resolution of the accumulator in radians
+		lc.Threshold,     // threshold: accumulator threshold for line detection
+		lc.MinLineLength, // minLineLength: segments shorter than this are rejected
+		lc.MaxLineGap,    // maxLineGap: max gap between points on the same line to link them
+	)
+
+	goodLines := make([]Line, 0, segments.Rows())
+	for i := 0; i < segments.Rows(); i++ {
+		seg := segments.GetVeciAt(i, 0)
+		p1 := image.Pt(int(seg[0]), int(seg[1]))
+		p2 := image.Pt(int(seg[2]), int(seg[3]))
+
+		if outputResults != "" {
+			gocv.Line(&mat, p1, p2, color.RGBA{0, 255, 0, 0}, 2)
+		}
+
+		if lc.Crop != nil {
+			// need to add the offset back so the segment is returned with respect to original image
+			p1 = p1.Add(lc.Crop.Min)
+			p2 = p2.Add(lc.Crop.Min)
+		}
+
+		goodLines = append(goodLines, Line{p1, p2})
+	}
+
+	if outputResults != "" {
+		if ok := gocv.IMWrite(outputResults, mat); !ok {
+			return nil, errors.New("failed to save the output image")
+		}
+	}
+
+	// order the segments longest first
+	sort.Slice(goodLines, func(i, j int) bool {
+		return lineLength(goodLines[i]) > lineLength(goodLines[j])
+	})
+	return goodLines, nil
+}
+
+func lineLength(l Line) float64 {
+	dx := float64(l.p2.X - l.p1.X)
+	dy := float64(l.p2.Y - l.p1.Y)
+	return math.Hypot(dx, dy)
+}
+
+func lineBoundingRect(l Line) image.Rectangle {
+	minX, maxX := l.p1.X, l.p2.X
+	if minX > maxX {
+		minX, maxX = maxX, minX
+	}
+	minY, maxY := l.p1.Y, l.p2.Y
+	if minY > maxY {
+		minY, maxY = maxY, minY
+	}
+	return image.Rectangle{Min: image.Point{X: minX, Y: minY}, Max: image.Point{X: maxX, Y: maxY}}
+}
@@ -0,0 +1,219 @@
+package hough
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.viam.com/rdk/components/camera"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/services/vision"
+	vis "go.viam.com/rdk/vision"
+	"go.viam.com/rdk/vision/classification"
+	objdet "go.viam.com/rdk/vision/objectdetection"
+	"go.viam.com/rdk/vision/viscapture"
+)
+
+const (
+	// LineModelName is shared with the circle detector's model name; the two are
+	// disambiguated by the family segment of their model triplet.
+	LineModelName = ModelName
+)
+
+// LineModel is this model's colon-delimited-triplet (viam:line-detector:hough-transform)
+var LineModel = resource.NewModel("viam", "line-detector", LineModelName)
+
+func init() {
+	resource.RegisterService(vision.API, LineModel, resource.Registration[vision.Service, *LineConfig]{
+		Constructor: newHoughLineTransformer,
+	})
+}
+
+type myHoughLineTransformer struct {
+	resource.Named
+	resource.AlwaysRebuild
+
+	logger logging.Logger
+	cam    camera.Camera
+	conf   *LineConfig
+
+	lastLinesMu sync.RWMutex
+	lastLines   []Line
+}
+
+func newHoughLineTransformer(ctx context.Context, deps resource.Dependencies, conf resource.Config, logger logging.Logger) (vision.Service, error) {
+
+	newConf, err := resource.NativeConfig[*LineConfig](conf)
+	if err != nil {
+		return nil, errors.Errorf("Could not assert proper config for %s", LineModelName)
+	}
+
+	h := &myHoughLineTransformer{
+		logger: logger,
+		conf:   newConf,
+	}
+
+	h.cam, err = camera.FromDependencies(deps, newConf.CameraName)
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (h *myHoughLineTransformer) DetectionsFromCamera(
+	ctx context.Context,
+	cameraName string,
+	extra map[string]interface{},
+) ([]objdet.Detection, error) {
+	colorImg, err := h.getImage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	detections, err := h.Detections(ctx, colorImg, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return detections, nil
+}
+
+func (h *myHoughLineTransformer) Detections(ctx context.Context, img image.Image, extra map[string]interface{}) ([]objdet.Detection, error) {
+	lines, err := vesselLines(img, h.conf, false, "")
+	if err != nil {
+		return nil, err
+	}
+
+	h.lastLinesMu.Lock()
+	h.lastLines = lines
+	h.lastLinesMu.Unlock()
+
+	return formatLineDetections(lines), nil
+}
+
+func (h *myHoughLineTransformer) ClassificationsFromCamera(
+	ctx context.Context,
+	cameraName string,
+	n int,
+	extra map[string]interface{},
+) (classification.Classifications, error) {
+	return nil, errUnimplemented
+}
+
+func (h *myHoughLineTransformer) Classifications(ctx context.Context, img image.Image,
+	n int, extra map[string]interface{},
+) (classification.Classifications, error) {
+	return nil, errUnimplemented
+}
+
+func (h *myHoughLineTransformer) GetProperties(ctx context.Context, extra map[string]interface{}) (*vision.Properties, error) {
+	return &vision.Properties{
+		DetectionSupported:      true,
+		ClassificationSupported: false,
+		ObjectPCDsSupported:     false,
+	}, nil
+}
+
+func (h *myHoughLineTransformer) GetObjectPointClouds(
+	ctx context.Context,
+	cameraName string,
+	extra map[string]interface{},
+) ([]*vis.Object, error) {
+	return nil, errUnimplemented
+}
+
+func (h *myHoughLineTransformer) CaptureAllFromCamera(
+	ctx context.Context,
+	cameraName string,
+	opt viscapture.CaptureOptions,
+	extra map[string]interface{},
+) (viscapture.VisCapture, error) {
+
+	colorImg, err := h.getImage(ctx)
+	if err != nil {
+		return viscapture.VisCapture{}, err
+	}
+
+	output := fmt.Sprintf("line-output-%d.jpg", rand.Int()%1000)
+
+	lines, err := vesselLines(colorImg, h.conf, false, output)
+	if err != nil {
+		return viscapture.VisCapture{}, err
+	}
+
+	h.lastLinesMu.Lock()
+	h.lastLines = lines
+	h.lastLinesMu.Unlock()
+	detections := formatLineDetections(lines)
+
+	croppedColorImg, err := openImage(output)
+	if err != nil {
+		return viscapture.VisCapture{}, err
+	}
+
+	os.Remove(output)
+
+	return viscapture.VisCapture{
+		Image:      croppedColorImg,
+		Detections: detections,
+	}, nil
+}
+
+func (h *myHoughLineTransformer) Close(ctx context.Context) error {
+	return nil
+}
+
+// DoCommand supports {"endpoints": true}, which returns the two endpoints of every
+// line segment from the most recent detection pass, since a bounding-rectangle
+// detection can't carry that information on its own.
+func (h *myHoughLineTransformer) DoCommand(ctx context.Context, cmd map[string]interface{}) (map[string]interface{}, error) {
+	if _, ok := cmd["endpoints"]; !ok {
+		return nil, errors.New("called DoCommand but nothing was executed")
+	}
+
+	h.lastLinesMu.RLock()
+	lastLines := h.lastLines
+	h.lastLinesMu.RUnlock()
+
+	segments := make([]map[string]interface{}, len(lastLines))
+	for i, l := range lastLines {
+		segments[i] = map[string]interface{}{
+			"p1": []int{l.p1.X, l.p1.Y},
+			"p2": []int{l.p2.X, l.p2.Y},
+		}
+	}
+
+	return map[string]interface{}{"segments": segments}, nil
+}
+
+func (h *myHoughLineTransformer) getImage(ctx context.Context) (image.Image, error) {
+	images, _, err := h.cam.Images(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var colorImg image.Image
+	for _, img := range images {
+		if img.SourceName == "color" {
+			colorImg = img.Image
+		}
+	}
+	return colorImg, nil
+}
+
+func formatLineDetections(lines []Line) []objdet.Detection {
+	var detections []objdet.Detection
+	for i, l := range lines {
+		rect := lineBoundingRect(l)
+		name := "line-" + strconv.Itoa(i)
+		detections = append(detections, objdet.NewDetection(rect, 1, name))
+	}
+	return detections
+}
@@ -0,0 +1,145 @@
+package hough
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+
+	"gocv.io/x/gocv"
+)
+
+// PreprocessConfig holds the crop / blur / edge-detection pipeline settings shared
+// by every Hough-based detector in this package. It is meant to be embedded in each
+// detector's own config struct, so its fields are left untagged where the default
+// (lowercased) JSON name is what we want and tagged where it isn't.
+type PreprocessConfig struct {
+	Crop     *image.Rectangle
+	SkipBlur bool `json:"skip_blur"`
+
+	// BlurType selects the noise-reduction stage: "none", "median" (default),
+	// "gaussian", or "bilateral". Ignored when SkipBlur is true.
+	BlurType string `json:"blur_type,omitempty"`
+	// BlurKernel is the kernel/aperture size used by the selected blur. Must be odd
+	// for median and gaussian blurs.
+	BlurKernel int `json:"blur_kernel,omitempty"`
+	// GaussianSigma is the sigmaX passed to gaussian blur; 0 lets OpenCV derive it
+	// from BlurKernel.
+	GaussianSigma float64 `json:"gaussian_sigma,omitempty"`
+
+	// CannyLow and CannyHigh, when both set, run gocv.Canny on the blurred gray
+	// image before handing it to the Hough stage. Leave both at 0 to skip Canny: the
+	// circle detector then relies on HoughCircles' own internal Canny (upper
+	// threshold param1), while the line detector runs HoughLinesP, which has no
+	// internal Canny, directly on the gray image.
+	CannyLow  float64 `json:"canny_low,omitempty"`
+	CannyHigh float64 `json:"canny_high,omitempty"`
+}
+
+func (pc *PreprocessConfig) setDefaults() {
+	pc.BlurType = "median"
+	pc.BlurKernel = 15
+}
+
+func (pc *PreprocessConfig) validate() error {
+	switch pc.BlurType {
+	case "", "none", "median", "gaussian", "bilateral":
+	default:
+		return fmt.Errorf(`blur_type must be one of "none", "median", "gaussian", "bilateral", got %q`, pc.BlurType)
+	}
+
+	if (pc.CannyLow > 0) != (pc.CannyHigh > 0) {
+		return errors.New("canny_low and canny_high must either both be set or both be left at 0")
+	}
+
+	// 0 means "let applyBlur pick its own default"; anything else must be a kernel
+	// OpenCV will actually accept for the selected blur.
+	if pc.BlurKernel != 0 {
+		switch pc.BlurType {
+		case "", "median", "gaussian":
+			if pc.BlurKernel < 1 || pc.BlurKernel%2 == 0 {
+				return fmt.Errorf("blur_kernel must be odd and >= 1 for %q blur, got %d", pc.BlurType, pc.BlurKernel)
+			}
+		}
+	}
+
+	return nil
+}
+
+// preprocessImage runs the crop -> BGR-to-gray -> optional blur -> optional Canny
+// pipeline shared by every Hough-based detector in this package. Both returned Mats
+// must be closed by the caller. When outputBlur is set, the blur and Canny stages
+// (whichever run) are written to disk as blurred.jpg and edges.jpg, so users can
+// tell which step is destroying their edges.
+func preprocessImage(img image.Image, pc *PreprocessConfig, outputBlur bool) (gocv.Mat, gocv.Mat, error) {
+	croppedImg := cropImage(img, pc.Crop)
+	mat := imageToMat(croppedImg)
+
+	gray := gocv.NewMat()
+	gocv.CvtColor(mat, &gray, gocv.ColorBGRToGray)
+
+	if !pc.SkipBlur {
+		if err := applyBlur(&gray, pc); err != nil {
+			mat.Close()
+			gray.Close()
+			return gocv.Mat{}, gocv.Mat{}, err
+		}
+		if outputBlur {
+			if ok := gocv.IMWrite("blurred.jpg", gray); !ok {
+				mat.Close()
+				gray.Close()
+				return gocv.Mat{}, gocv.Mat{}, errors.New("failed to save the output image")
+			}
+		}
+	}
+
+	if pc.CannyLow > 0 && pc.CannyHigh > 0 {
+		gocv.Canny(gray, &gray, float32(pc.CannyLow), float32(pc.CannyHigh))
+		if outputBlur {
+			if ok := gocv.IMWrite("edges.jpg", gray); !ok {
+				mat.Close()
+				gray.Close()
+				return gocv.Mat{}, gocv.Mat{}, errors.New("failed to save the output image")
+			}
+		}
+	}
+
+	return mat, gray, nil
+}
+
+func applyBlur(gray *gocv.Mat, pc *PreprocessConfig) error {
+	kernel := pc.BlurKernel
+	if kernel <= 0 {
+		kernel = 15
+	}
+
+	switch pc.BlurType {
+	case "", "median":
+		gocv.MedianBlur(*gray, gray, kernel)
+	case "gaussian":
+		gocv.GaussianBlur(*gray, gray, image.Pt(kernel, kernel), pc.GaussianSigma, pc.GaussianSigma, gocv.BorderDefault)
+	case "bilateral":
+		filtered := gocv.NewMat()
+		defer filtered.Close()
+		gocv.BilateralFilter(*gray, &filtered, kernel, float64(kernel)*2, float64(kernel)/2)
+		filtered.CopyTo(gray)
+	case "none":
+		// no-op
+	default:
+		return fmt.Errorf("unknown blur_type %q", pc.BlurType)
+	}
+
+	return nil
+}
+
+func cropImage(src image.Image, crop *image.Rectangle) image.Image {
+	if crop == nil {
+		return src
+	}
+	// Create a new RGBA image with the size of the crop rectangle
+	croppedImg := image.NewRGBA(image.Rect(0, 0, crop.Dx(), crop.Dy()))
+
+	// Adjust the draw point to correctly position the cropped area
+	draw.Draw(croppedImg, croppedImg.Bounds(), src, crop.Min, draw.Src)
+	return croppedImg
+}
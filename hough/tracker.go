@@ -0,0 +1,205 @@
+package hough
+
+import (
+	"image"
+	"math"
+	"sort"
+	"sync"
+)
+
+// kalman1D is a constant-velocity Kalman filter over a single scalar (position +
+// velocity). myHoughTransformer keeps three of these per track -- for cx, cy, and r
+// -- rather than one 5-state filter, since the three dimensions never interact.
+type kalman1D struct {
+	x, v float64
+	p    [2][2]float64
+	q, r float64
+}
+
+func newKalman1D(initial float64) *kalman1D {
+	return &kalman1D{
+		x: initial,
+		p: [2][2]float64{{1, 0}, {0, 1}},
+		q: 1e-2,
+		r: 4,
+	}
+}
+
+// predict advances the filter one step under the constant-velocity model and
+// returns the new position estimate. It mutates the filter in place, so a track
+// that goes unmatched this frame is left holding its predicted state.
+func (k *kalman1D) predict() float64 {
+	k.x += k.v
+
+	p00, p01, p10, p11 := k.p[0][0], k.p[0][1], k.p[1][0], k.p[1][1]
+	k.p[0][0] = p00 + p01 + p10 + p11 + k.q
+	k.p[0][1] = p01 + p11
+	k.p[1][0] = p10 + p11
+	k.p[1][1] = p11 + k.q
+
+	return k.x
+}
+
+func (k *kalman1D) update(z float64) {
+	innovation := z - k.x
+	s := k.p[0][0] + k.r
+	k0 := k.p[0][0] / s
+	k1 := k.p[1][0] / s
+
+	k.x += k0 * innovation
+	k.v += k1 * innovation
+
+	p00, p01 := k.p[0][0], k.p[0][1]
+	k.p[0][0] -= k0 * p00
+	k.p[0][1] -= k0 * p01
+	k.p[1][0] -= k1 * p00
+	k.p[1][1] -= k1 * p01
+}
+
+// circleTrack is a single circle followed across frames, identified by a stable id.
+type circleTrack struct {
+	id         int
+	kx, ky, kr *kalman1D
+	missed     int
+}
+
+func newCircleTrack(id int, c Circle) *circleTrack {
+	return &circleTrack{
+		id: id,
+		kx: newKalman1D(float64(c.center.X)),
+		ky: newKalman1D(float64(c.center.Y)),
+		kr: newKalman1D(float64(c.radius)),
+	}
+}
+
+func (t *circleTrack) predict() Circle {
+	x := t.kx.predict()
+	y := t.ky.predict()
+	r := t.kr.predict()
+	return Circle{center: image.Pt(int(math.Round(x)), int(math.Round(y))), radius: int(math.Round(r))}
+}
+
+func (t *circleTrack) update(c Circle) {
+	t.kx.update(float64(c.center.X))
+	t.ky.update(float64(c.center.Y))
+	t.kr.update(float64(c.radius))
+	t.missed = 0
+}
+
+func (t *circleTrack) circle() Circle {
+	return Circle{
+		center: image.Pt(int(math.Round(t.kx.x)), int(math.Round(t.ky.x))),
+		radius: int(math.Round(t.kr.x)),
+	}
+}
+
+// trackedCircle pairs a persistent track id with its current (matched or predicted)
+// circle.
+type trackedCircle struct {
+	id     int
+	circle Circle
+}
+
+// tracker assigns stable ids to circles across successive calls to update, matching
+// each frame's raw detections against the previous frame's tracks by greedy
+// nearest-center-distance, gated by a max center drift and max radius change.
+// Unmatched tracks survive on their Kalman prediction until they exceed the
+// configured missed-frame budget, at which point they're evicted.
+type tracker struct {
+	mu     sync.Mutex
+	nextID int
+	tracks []*circleTrack
+}
+
+func newTracker() *tracker {
+	return &tracker{}
+}
+
+func (tr *tracker) update(circles []Circle, maxCenterDriftPx, maxRadiusChangePx float64, maxMissedFrames int) []trackedCircle {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	predicted := make([]Circle, len(tr.tracks))
+	for i, t := range tr.tracks {
+		predicted[i] = t.predict()
+	}
+
+	type candidate struct {
+		trackIdx, circleIdx int
+		dist                float64
+	}
+	var candidates []candidate
+	for ti, p := range predicted {
+		for ci, c := range circles {
+			dist := centerDistance(p.center, c.center)
+			if dist > maxCenterDriftPx {
+				continue
+			}
+			if math.Abs(float64(p.radius-c.radius)) > maxRadiusChangePx {
+				continue
+			}
+			candidates = append(candidates, candidate{ti, ci, dist})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].dist < candidates[j].dist })
+
+	matchedTrack := make([]bool, len(tr.tracks))
+	matchedCircle := make([]bool, len(circles))
+	for _, cand := range candidates {
+		if matchedTrack[cand.trackIdx] || matchedCircle[cand.circleIdx] {
+			continue
+		}
+		matchedTrack[cand.trackIdx] = true
+		matchedCircle[cand.circleIdx] = true
+		tr.tracks[cand.trackIdx].update(circles[cand.circleIdx])
+	}
+
+	result := make([]trackedCircle, 0, len(tr.tracks)+len(circles))
+	live := tr.tracks[:0]
+	for i, t := range tr.tracks {
+		if !matchedTrack[i] {
+			t.missed++
+			if t.missed > maxMissedFrames {
+				continue // evict
+			}
+		}
+		live = append(live, t)
+		result = append(result, trackedCircle{id: t.id, circle: t.circle()})
+	}
+	tr.tracks = live
+
+	for ci, c := range circles {
+		if matchedCircle[ci] {
+			continue
+		}
+		t := newCircleTrack(tr.nextID, c)
+		tr.nextID++
+		tr.tracks = append(tr.tracks, t)
+		result = append(result, trackedCircle{id: t.id, circle: c})
+	}
+
+	return result
+}
+
+func (tr *tracker) reset() {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.tracks = nil
+	tr.nextID = 0
+}
+
+func (tr *tracker) dump() []trackedCircle {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	out := make([]trackedCircle, len(tr.tracks))
+	for i, t := range tr.tracks {
+		out[i] = trackedCircle{id: t.id, circle: t.circle()}
+	}
+	return out
+}
+
+func centerDistance(a, b image.Point) float64 {
+	dx := float64(a.X - b.X)
+	dy := float64(a.Y - b.Y)
+	return math.Hypot(dx, dy)
+}
@@ -13,5 +13,6 @@ func main() {
 	module.ModularMain(
 		hough.ModelName,
 		resource.APIModel{API: vision.API, Model: hough.Model},
+		resource.APIModel{API: vision.API, Model: hough.LineModel},
 	)
 }